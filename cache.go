@@ -0,0 +1,144 @@
+package dns_resolver
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// maxNegativeTTL bounds how long NXDOMAIN/NODATA answers are cached for,
+// regardless of what the SOA MINIMUM field says.
+const maxNegativeTTL = 5 * time.Minute
+
+// cacheKey identifies a cached response by the tuple DNS actually varies on.
+type cacheKey struct {
+	qname  string
+	qtype  uint16
+	qclass uint16
+}
+
+type cacheEntry struct {
+	msg     *dns.Msg
+	expires time.Time
+	elem    *list.Element
+}
+
+// respCache is a small, concurrency-safe LRU cache of *dns.Msg keyed by
+// (qname, qtype, qclass), honoring per-entry TTLs based on wall clock
+// expiration. A MiekgResolver's cache is shared across the goroutines
+// LookupIPFullContext fires for concurrent A/AAAA queries, so all access
+// goes through mu.
+type respCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[cacheKey]*cacheEntry
+	order      *list.List // front = most recently used
+}
+
+func newRespCache(maxEntries int) *respCache {
+	return &respCache{
+		maxEntries: maxEntries,
+		entries:    make(map[cacheKey]*cacheEntry),
+		order:      list.New(),
+	}
+}
+
+func (c *respCache) get(key cacheKey) (*dns.Msg, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		c.removeLocked(key, e)
+		return nil, false
+	}
+	c.order.MoveToFront(e.elem)
+	return e.msg, true
+}
+
+func (c *respCache) set(key cacheKey, msg *dns.Msg, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.msg = msg
+		e.expires = time.Now().Add(ttl)
+		c.order.MoveToFront(e.elem)
+		return
+	}
+	elem := c.order.PushFront(key)
+	c.entries[key] = &cacheEntry{msg: msg, expires: time.Now().Add(ttl), elem: elem}
+
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		oldKey := oldest.Value.(cacheKey)
+		c.removeLocked(oldKey, c.entries[oldKey])
+	}
+}
+
+// removeLocked evicts an entry. Callers must hold c.mu.
+func (c *respCache) removeLocked(key cacheKey, e *cacheEntry) {
+	c.order.Remove(e.elem)
+	delete(c.entries, key)
+}
+
+func (c *respCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[cacheKey]*cacheEntry)
+	c.order.Init()
+}
+
+// EnableCache turns on in-memory response caching, keyed by (qname, qtype,
+// qclass). maxEntries bounds the number of cached responses; once exceeded,
+// the least recently used entry is evicted. EnableCache may be called again
+// to resize the cache, which also clears it.
+func (r *MiekgResolver) EnableCache(maxEntries int) {
+	r.cache = newRespCache(maxEntries)
+}
+
+// Purge removes all cached responses. It is a no-op if caching is not
+// enabled.
+func (r *MiekgResolver) Purge() {
+	if r.cache != nil {
+		r.cache.purge()
+	}
+}
+
+// cacheTTL computes the duration a response should be cached for: the
+// minimum TTL across all returned RRs, or for negative responses
+// (NXDOMAIN/NODATA) the SOA MINIMUM field per RFC 2308, bounded by
+// maxNegativeTTL.
+func cacheTTL(in *dns.Msg) time.Duration {
+	if len(in.Answer) == 0 {
+		for _, rr := range in.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				ttl := time.Duration(soa.Minttl) * time.Second
+				if ttl > maxNegativeTTL {
+					ttl = maxNegativeTTL
+				}
+				return ttl
+			}
+		}
+		return maxNegativeTTL
+	}
+
+	min := uint32(0)
+	for i, rr := range in.Answer {
+		ttl := rr.Header().Ttl
+		if i == 0 || ttl < min {
+			min = ttl
+		}
+	}
+	return time.Duration(min) * time.Second
+}
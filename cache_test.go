@@ -0,0 +1,52 @@
+package dns_resolver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestCacheTTL(t *testing.T) {
+	t.Run("positive answer uses minimum RR ttl", func(t *testing.T) {
+		m := new(dns.Msg)
+		m.Answer = []dns.RR{
+			&dns.A{Hdr: dns.RR_Header{Ttl: 300}},
+			&dns.A{Hdr: dns.RR_Header{Ttl: 60}},
+			&dns.A{Hdr: dns.RR_Header{Ttl: 120}},
+		}
+		if got, want := cacheTTL(m), 60*time.Second; got != want {
+			t.Errorf("cacheTTL = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("negative response uses soa minttl", func(t *testing.T) {
+		m := new(dns.Msg)
+		m.Rcode = dns.RcodeNameError
+		m.Ns = []dns.RR{
+			&dns.SOA{Hdr: dns.RR_Header{}, Minttl: 30},
+		}
+		if got, want := cacheTTL(m), 30*time.Second; got != want {
+			t.Errorf("cacheTTL = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("negative response without soa falls back to max negative ttl", func(t *testing.T) {
+		m := new(dns.Msg)
+		m.Rcode = dns.RcodeNameError
+		if got, want := cacheTTL(m), maxNegativeTTL; got != want {
+			t.Errorf("cacheTTL = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("soa minttl above max negative ttl is bounded", func(t *testing.T) {
+		m := new(dns.Msg)
+		m.Rcode = dns.RcodeNameError
+		m.Ns = []dns.RR{
+			&dns.SOA{Hdr: dns.RR_Header{}, Minttl: uint32(maxNegativeTTL/time.Second) + 3600},
+		}
+		if got, want := cacheTTL(m), maxNegativeTTL; got != want {
+			t.Errorf("cacheTTL = %v, want %v", got, want)
+		}
+	})
+}
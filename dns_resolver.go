@@ -3,11 +3,15 @@
 package dns_resolver
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
 	"errors"
 	"math/rand"
 	"net"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/miekg/dns"
@@ -15,60 +19,120 @@ import (
 
 var dnsTimeout = 2 * time.Second
 
-// DnsResolver represents a dns resolver
-type DnsResolver struct {
+// defaultEDNS0BufSize is the UDP payload size advertised when DNSSEC is
+// requested but EDNS0BufSize was left unset.
+const defaultEDNS0BufSize = 4096
+
+// MiekgResolver represents a dns resolver
+type MiekgResolver struct {
 	Servers         []string
 	RetryTimes      int
 	ReuseConnection bool
-	r               *rand.Rand
-	conns           map[string]*dns.Conn
+	SearchList      []string
+	Ndots           int
+	// Timeout bounds each query attempt. If zero, the package default
+	// (2s) is used.
+	Timeout time.Duration
+	// Protocol selects the transport used for queries: "udp" (default),
+	// "tcp", or "tcp-tls" for DNS over TLS (RFC 7858).
+	Protocol  string
+	TLSConfig *tls.Config
+	// DialFunc, if set, is used in place of dns.DialTimeout /
+	// dns.DialTimeoutWithTLS to obtain connections, letting tests inject a
+	// fake dns.Conn instead of hitting the network.
+	DialFunc ResolverDialFunc
+	// MaxConnsPerServer bounds the number of pooled connections kept open
+	// to a single server when ReuseConnection is set. If zero, one
+	// connection per server is reused (the historical behavior).
+	MaxConnsPerServer int
+	// RotationStrategy selects how Servers are picked across queries.
+	// Defaults to Random.
+	RotationStrategy RotationStrategy
+	// EDNS0BufSize is the UDP payload size advertised via an EDNS0 OPT
+	// RR. If zero but DNSSEC is set, 4096 is used.
+	EDNS0BufSize uint16
+	// DNSSEC, if set, requests DNSSEC records by attaching an EDNS0 OPT
+	// RR with the DO bit set.
+	DNSSEC bool
+
+	r            *rand.Rand
+	mu           sync.Mutex // guards conns, rrIndex and stickyServer
+	conns        map[string]*connPool
+	rrIndex      int
+	stickyServer string
+	cache        *respCache
 }
 
-// New initializes DnsResolver.
-func New(servers []string) *DnsResolver {
+// New initializes MiekgResolver.
+func New(servers []string) *MiekgResolver {
 	for i := range servers {
-		servers[i] += ":53"
+		servers[i] = net.JoinHostPort(servers[i], "53")
 	}
 
-	resolver := &DnsResolver{
+	resolver := &MiekgResolver{
 		Servers:    servers,
 		RetryTimes: len(servers) * 2,
+		Ndots:      defaultNdots,
 	}
 	resolver.r = rand.New(rand.NewSource(time.Now().UnixNano()))
-	resolver.conns = make(map[string]*dns.Conn)
+	resolver.conns = make(map[string]*connPool)
 
 	return resolver
 }
 
-// NewFromResolvConf initializes DnsResolver from resolv.conf like file.
-func NewFromResolvConf(path string) (*DnsResolver, error) {
+// NewFromResolvConf initializes MiekgResolver from resolv.conf like file.
+func NewFromResolvConf(path string) (*MiekgResolver, error) {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return &DnsResolver{}, errors.New("no such file or directory: " + path)
+		return &MiekgResolver{}, errors.New("no such file or directory: " + path)
 	}
 	config, err := dns.ClientConfigFromFile(path)
 	if err != nil {
 		return nil, err
 	}
-	return New(config.Servers), nil
+	resolver := New(config.Servers)
+	resolver.SearchList = config.Search
+	resolver.Ndots = config.Ndots
+	if hasRotateOption(path) {
+		resolver.RotationStrategy = RoundRobin
+	}
+	return resolver, nil
 }
 
-func (r *DnsResolver) getConnection(address string) (*dns.Conn, error) {
-	conn, ok := r.conns[address]
-	if !ok {
-		c, err := dns.DialTimeout("udp", address, dnsTimeout)
-		if err != nil {
-			return nil, err
+// hasRotateOption reports whether path (a resolv.conf like file) sets
+// "options rotate". dns.ClientConfig does not expose parsed options, so
+// this re-scans the file for the one bit we need.
+func hasRotateOption(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "options" {
+			continue
+		}
+		for _, opt := range fields[1:] {
+			if opt == "rotate" {
+				return true
+			}
 		}
-		r.conns[address] = c
-		conn = c
 	}
-	return conn, nil
+	return false
 }
 
 // LookupHost returns IP addresses of provied host.
 // In case of timeout retries query RetryTimes times.
-func (r *DnsResolver) LookupHost(host string) (result []net.IP, err error) {
-	in, err := r.performWithRetry(host, r.RetryTimes, dns.TypeA)
+func (r *MiekgResolver) LookupHost(host string) (result []net.IP, err error) {
+	return r.LookupHostContext(context.Background(), host)
+}
+
+// LookupHostContext behaves like LookupHost but aborts as soon as ctx is
+// done, instead of waiting out the full retry/timeout budget.
+func (r *MiekgResolver) LookupHostContext(ctx context.Context, host string) (result []net.IP, err error) {
+	in, err := r.performWithRetry(ctx, host, r.RetryTimes, dns.TypeA)
 
 	if err != nil {
 		return nil, err
@@ -83,8 +147,14 @@ func (r *DnsResolver) LookupHost(host string) (result []net.IP, err error) {
 
 // LookupHostFull returns IP addresses and CNAMES of provied host.
 // In case of timeout retries query RetryTimes times.
-func (r *DnsResolver) LookupHostFull(host string) (result []net.IP, resultCname []string, err error) {
-	in, err := r.performWithRetry(host, r.RetryTimes, dns.TypeA)
+func (r *MiekgResolver) LookupHostFull(host string) (result []net.IP, resultCname []string, err error) {
+	return r.LookupHostFullContext(context.Background(), host)
+}
+
+// LookupHostFullContext behaves like LookupHostFull but aborts as soon as
+// ctx is done, instead of waiting out the full retry/timeout budget.
+func (r *MiekgResolver) LookupHostFullContext(ctx context.Context, host string) (result []net.IP, resultCname []string, err error) {
+	in, err := r.performWithRetry(ctx, host, r.RetryTimes, dns.TypeA)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -100,39 +170,89 @@ func (r *DnsResolver) LookupHostFull(host string) (result []net.IP, resultCname
 	return result, resultCname, err
 }
 
-func (r *DnsResolver) performWithRetry(host string, triesLeft int, reqType uint16) (result *dns.Msg, err error) {
+// Exchange sends m to a server chosen by RotationStrategy and returns the raw
+// response, without the retry or caching logic used by the Lookup
+// methods. It satisfies the Resolver interface.
+func (r *MiekgResolver) Exchange(m *dns.Msg) (*dns.Msg, error) {
+	return r.ExchangeContext(context.Background(), m)
+}
+
+// ExchangeContext behaves like Exchange but aborts as soon as ctx is done.
+func (r *MiekgResolver) ExchangeContext(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	server := r.pickServer()
+	proto := r.Protocol
+	if proto == "" {
+		proto = "udp"
+	}
+	return r.exchange(ctx, m, server, proto, 1)
+}
+
+func (r *MiekgResolver) performWithRetry(ctx context.Context, host string, triesLeft int, reqType uint16) (result *dns.Msg, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	key := cacheKey{qname: dns.Fqdn(host), qtype: reqType, qclass: dns.ClassINET}
+	if r.cache != nil {
+		if cached, ok := r.cache.get(key); ok {
+			if cached.Rcode != dns.RcodeSuccess {
+				return nil, errors.New(dns.RcodeToString[cached.Rcode])
+			}
+			return cached, nil
+		}
+	}
+
 	m1 := new(dns.Msg)
 	m1.Id = dns.Id()
 	m1.RecursionDesired = true
 	m1.Question = make([]dns.Question, 1)
-	m1.Question[0] = dns.Question{dns.Fqdn(host), reqType, dns.ClassINET}
+	m1.Question[0] = dns.Question{Name: dns.Fqdn(host), Qtype: reqType, Qclass: dns.ClassINET}
 
-	server := r.Servers[r.r.Intn(len(r.Servers))]
+	if r.DNSSEC || r.EDNS0BufSize > 0 {
+		bufSize := r.EDNS0BufSize
+		if bufSize == 0 {
+			bufSize = defaultEDNS0BufSize
+		}
+		m1.SetEdns0(bufSize, r.DNSSEC)
+	}
 
-	var in *dns.Msg
+	server := r.pickServer()
 
-	if r.ReuseConnection {
-		connection, err := r.getConnection(server)
-		if err != nil {
-			return nil, err
-		}
-		connection.WriteMsg(m1)
-		in, err = connection.ReadMsg()
-	} else {
-		in, err = dns.Exchange(m1, server)
+	proto := r.Protocol
+	if proto == "" {
+		proto = "udp"
 	}
 
+	in, err := r.exchange(ctx, m1, server, proto, triesLeft+1)
+
 	if err != nil {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
 		if strings.HasSuffix(err.Error(), "i/o timeout") && triesLeft > 0 {
 			triesLeft--
-			return r.performWithRetry(host, triesLeft, reqType)
+			return r.performWithRetry(ctx, host, triesLeft, reqType)
 		}
 		return result, err
 	}
 
+	if in != nil && in.Truncated && proto == "udp" {
+		in, err = r.exchange(ctx, m1, server, "tcp", triesLeft+1)
+		if err != nil {
+			return result, err
+		}
+	}
+
 	if in != nil && in.Rcode != dns.RcodeSuccess {
+		if r.cache != nil && in.Rcode == dns.RcodeNameError {
+			r.cache.set(key, in, cacheTTL(in))
+		}
 		return result, errors.New(dns.RcodeToString[in.Rcode])
 	}
 
+	if r.cache != nil {
+		r.cache.set(key, in, cacheTTL(in))
+	}
+
 	return in, nil
 }
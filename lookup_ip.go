@@ -0,0 +1,122 @@
+package dns_resolver
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// defaultNdots mirrors glibc/Go's default "ndots" value, applied by New
+// when a MiekgResolver is constructed without one. Ndots:0 is a legal,
+// distinct configuration (always try the bare name first) and must not
+// be coerced back up to this default once set.
+const defaultNdots = 1
+
+// searchCandidates builds the ordered list of FQDNs to try for host,
+// following the resolv.conf search algorithm. A name with a trailing dot,
+// or any name when SearchList is empty, is tried only as the absolute
+// name. Otherwise a name with at least Ndots dots is tried absolute first,
+// then each SearchList suffix is tried in order, and finally the bare
+// absolute name if it wasn't already tried first.
+func (r *MiekgResolver) searchCandidates(host string) []string {
+	if strings.HasSuffix(host, ".") || len(r.SearchList) == 0 {
+		return []string{dns.Fqdn(host)}
+	}
+
+	var candidates []string
+	triedAbsolute := strings.Count(host, ".") >= r.Ndots
+	if triedAbsolute {
+		candidates = append(candidates, dns.Fqdn(host))
+	}
+	for _, suffix := range r.SearchList {
+		candidates = append(candidates, dns.Fqdn(host+"."+suffix))
+	}
+	if !triedAbsolute {
+		candidates = append(candidates, dns.Fqdn(host))
+	}
+	return candidates
+}
+
+type ipLookupResult struct {
+	in  *dns.Msg
+	err error
+}
+
+// lookupIPForName fires A and AAAA queries for name in parallel and merges
+// their answers, mirroring goLookupIPOrder.
+func (r *MiekgResolver) lookupIPForName(ctx context.Context, name string) (ips []net.IP, cnames []string, err error) {
+	ch := make(chan ipLookupResult, 2)
+	go func() {
+		in, err := r.performWithRetry(ctx, name, r.RetryTimes, dns.TypeA)
+		ch <- ipLookupResult{in, err}
+	}()
+	go func() {
+		in, err := r.performWithRetry(ctx, name, r.RetryTimes, dns.TypeAAAA)
+		ch <- ipLookupResult{in, err}
+	}()
+
+	var firstErr error
+	var gotAny bool
+	for i := 0; i < 2; i++ {
+		res := <-ch
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			continue
+		}
+		gotAny = true
+		for _, record := range res.in.Answer {
+			switch t := record.(type) {
+			case *dns.A:
+				ips = append(ips, t.A)
+			case *dns.AAAA:
+				ips = append(ips, t.AAAA)
+			case *dns.CNAME:
+				cnames = append(cnames, t.Target)
+			}
+		}
+	}
+	if !gotAny {
+		return nil, nil, firstErr
+	}
+	return ips, cnames, nil
+}
+
+// LookupIP returns the A and AAAA addresses of host, looked up in parallel.
+// If SearchList is set, candidate FQDNs are tried in resolv.conf order and
+// the first candidate to return at least one address wins; later
+// candidates are not consulted once one has succeeded.
+func (r *MiekgResolver) LookupIP(host string) (result []net.IP, err error) {
+	return r.LookupIPContext(context.Background(), host)
+}
+
+// LookupIPContext behaves like LookupIP but aborts as soon as ctx is done.
+func (r *MiekgResolver) LookupIPContext(ctx context.Context, host string) (result []net.IP, err error) {
+	result, _, err = r.LookupIPFullContext(ctx, host)
+	return result, err
+}
+
+// LookupIPFull behaves like LookupIP but also returns any CNAMEs
+// encountered while resolving host.
+func (r *MiekgResolver) LookupIPFull(host string) (result []net.IP, resultCname []string, err error) {
+	return r.LookupIPFullContext(context.Background(), host)
+}
+
+// LookupIPFullContext behaves like LookupIPFull but aborts as soon as ctx
+// is done.
+func (r *MiekgResolver) LookupIPFullContext(ctx context.Context, host string) (result []net.IP, resultCname []string, err error) {
+	for _, name := range r.searchCandidates(host) {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, nil, ctxErr
+		}
+		ips, cnames, lerr := r.lookupIPForName(ctx, name)
+		if lerr == nil && len(ips) > 0 {
+			return ips, cnames, nil
+		}
+		err = lerr
+	}
+	return nil, nil, err
+}
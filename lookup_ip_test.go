@@ -0,0 +1,60 @@
+package dns_resolver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchCandidates(t *testing.T) {
+	cases := []struct {
+		name       string
+		host       string
+		ndots      int
+		searchList []string
+		want       []string
+	}{
+		{
+			name: "no search list returns fqdn only",
+			host: "foo",
+			want: []string{"foo."},
+		},
+		{
+			name:       "trailing dot is already qualified",
+			host:       "foo.",
+			ndots:      1,
+			searchList: []string{"example.com"},
+			want:       []string{"foo."},
+		},
+		{
+			name:       "below ndots tries suffixes before bare name",
+			host:       "foo",
+			ndots:      2,
+			searchList: []string{"example.com", "example.net"},
+			want:       []string{"foo.example.com.", "foo.example.net.", "foo."},
+		},
+		{
+			name:       "meets ndots tries absolute first",
+			host:       "foo.bar",
+			ndots:      1,
+			searchList: []string{"example.com"},
+			want:       []string{"foo.bar.", "foo.bar.example.com."},
+		},
+		{
+			name:       "ndots 0 always tries bare name first",
+			host:       "foo",
+			ndots:      0,
+			searchList: []string{"example.com"},
+			want:       []string{"foo.", "foo.example.com."},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &MiekgResolver{Ndots: tc.ndots, SearchList: tc.searchList}
+			got := r.searchCandidates(tc.host)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("searchCandidates(%q) = %v, want %v", tc.host, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package dns_resolver
+
+import (
+	"context"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Lookup queries host for records of qtype and returns the raw response,
+// reusing the same retry and caching behavior as the other Lookup
+// methods. It lets callers reach record types this package has no typed
+// helper for.
+func (r *MiekgResolver) Lookup(host string, qtype uint16) (*dns.Msg, error) {
+	return r.LookupContext(context.Background(), host, qtype)
+}
+
+// LookupContext behaves like Lookup but aborts as soon as ctx is done.
+func (r *MiekgResolver) LookupContext(ctx context.Context, host string, qtype uint16) (*dns.Msg, error) {
+	return r.performWithRetry(ctx, host, r.RetryTimes, qtype)
+}
+
+// LookupMX returns the MX records of host.
+func (r *MiekgResolver) LookupMX(host string) ([]*dns.MX, error) {
+	in, err := r.Lookup(host, dns.TypeMX)
+	if err != nil {
+		return nil, err
+	}
+	var result []*dns.MX
+	for _, record := range in.Answer {
+		if t, ok := record.(*dns.MX); ok {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+// LookupTXT returns the TXT records of host, one string per record with
+// its segments concatenated, matching net.LookupTXT.
+func (r *MiekgResolver) LookupTXT(host string) ([]string, error) {
+	in, err := r.Lookup(host, dns.TypeTXT)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, record := range in.Answer {
+		if t, ok := record.(*dns.TXT); ok {
+			result = append(result, strings.Join(t.Txt, ""))
+		}
+	}
+	return result, nil
+}
+
+// LookupSRV returns the SRV records of host.
+func (r *MiekgResolver) LookupSRV(host string) ([]*dns.SRV, error) {
+	in, err := r.Lookup(host, dns.TypeSRV)
+	if err != nil {
+		return nil, err
+	}
+	var result []*dns.SRV
+	for _, record := range in.Answer {
+		if t, ok := record.(*dns.SRV); ok {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+// LookupNS returns the NS records of host.
+func (r *MiekgResolver) LookupNS(host string) ([]*dns.NS, error) {
+	in, err := r.Lookup(host, dns.TypeNS)
+	if err != nil {
+		return nil, err
+	}
+	var result []*dns.NS
+	for _, record := range in.Answer {
+		if t, ok := record.(*dns.NS); ok {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+// LookupPTR returns the PTR targets of host, typically an in-addr.arpa or
+// ip6.arpa reverse lookup name.
+func (r *MiekgResolver) LookupPTR(host string) ([]string, error) {
+	in, err := r.Lookup(host, dns.TypePTR)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, record := range in.Answer {
+		if t, ok := record.(*dns.PTR); ok {
+			result = append(result, t.Ptr)
+		}
+	}
+	return result, nil
+}
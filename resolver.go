@@ -0,0 +1,100 @@
+package dns_resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/miekg/dns"
+)
+
+// Resolver is the common interface implemented by MiekgResolver and
+// SystemResolver, letting callers swap the lookup backend.
+type Resolver interface {
+	LookupHost(host string) ([]net.IP, error)
+	LookupHostFull(host string) ([]net.IP, []string, error)
+	LookupIP(host string) ([]net.IP, error)
+	Exchange(m *dns.Msg) (*dns.Msg, error)
+}
+
+// Backend selects which Resolver implementation NewResolver constructs.
+type Backend string
+
+const (
+	// BackendMiekgDNS uses MiekgResolver, talking to the configured
+	// upstream Servers directly.
+	BackendMiekgDNS Backend = "miekgdns"
+	// BackendGolang delegates to net.DefaultResolver, i.e. the host's
+	// standard resolution mechanism (analogous to Resolver.PreferGo=false
+	// in the standard library).
+	BackendGolang Backend = "golang"
+)
+
+// ResolverDialFunc dials a connection for MiekgResolver, allowing tests to
+// inject a fake dns.Conn instead of hitting the network.
+type ResolverDialFunc func(network, address string) (*dns.Conn, error)
+
+// Config configures NewResolver.
+type Config struct {
+	Servers  []string
+	Backend  Backend
+	DialFunc ResolverDialFunc
+}
+
+// NewResolver picks a Resolver implementation based on cfg.Backend,
+// defaulting to BackendMiekgDNS.
+func NewResolver(cfg Config) Resolver {
+	if cfg.Backend == BackendGolang {
+		return NewSystemResolver()
+	}
+	r := New(cfg.Servers)
+	r.DialFunc = cfg.DialFunc
+	return r
+}
+
+// SystemResolver implements Resolver on top of net.DefaultResolver, i.e.
+// the host's standard (cgo or Go-native) resolution mechanism.
+type SystemResolver struct {
+	Resolver *net.Resolver
+}
+
+// NewSystemResolver initializes a SystemResolver backed by
+// net.DefaultResolver.
+func NewSystemResolver() *SystemResolver {
+	return &SystemResolver{Resolver: net.DefaultResolver}
+}
+
+// LookupHost returns the IP addresses of host via net.Resolver.LookupIPAddr.
+func (s *SystemResolver) LookupHost(host string) ([]net.IP, error) {
+	addrs, err := s.Resolver.LookupIPAddr(context.Background(), host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.IP
+	}
+	return ips, nil
+}
+
+// LookupHostFull returns the IP addresses of host. net.Resolver does not
+// expose intermediate CNAMEs, so the second return value is always nil.
+func (s *SystemResolver) LookupHostFull(host string) ([]net.IP, []string, error) {
+	ips, err := s.LookupHost(host)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ips, nil, nil
+}
+
+// LookupIP returns the IP addresses of host, equivalent to LookupHost for
+// SystemResolver since net.Resolver already resolves A and AAAA together.
+func (s *SystemResolver) LookupIP(host string) ([]net.IP, error) {
+	return s.LookupHost(host)
+}
+
+// Exchange is not supported by SystemResolver: net.Resolver does not
+// expose raw DNS message exchange.
+func (s *SystemResolver) Exchange(m *dns.Msg) (*dns.Msg, error) {
+	return nil, errors.New("dns_resolver: Exchange is not supported by SystemResolver")
+}
@@ -0,0 +1,47 @@
+package dns_resolver
+
+// RotationStrategy selects how MiekgResolver picks among multiple
+// configured Servers for each query.
+type RotationStrategy int
+
+const (
+	// Random picks a server uniformly at random for every query. This is
+	// the default, matching the resolver's historical behavior.
+	Random RotationStrategy = iota
+	// RoundRobin cycles through Servers in order, implementing the
+	// resolv.conf "options rotate" behavior (RFC 2308).
+	RoundRobin
+	// Sticky keeps using the same server once one has been picked,
+	// falling back to picking a new one only if Servers no longer
+	// contains it.
+	Sticky
+)
+
+// pickServer selects the upstream server to use for the next query,
+// according to r.RotationStrategy.
+func (r *MiekgResolver) pickServer() string {
+	switch r.RotationStrategy {
+	case RoundRobin:
+		r.mu.Lock()
+		index := r.rrIndex % len(r.Servers)
+		r.rrIndex++
+		r.mu.Unlock()
+		return r.Servers[index]
+	case Sticky:
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.stickyServer != "" {
+			for _, s := range r.Servers {
+				if s == r.stickyServer {
+					return s
+				}
+			}
+		}
+		r.stickyServer = r.Servers[r.r.Intn(len(r.Servers))]
+		return r.stickyServer
+	default:
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.Servers[r.r.Intn(len(r.Servers))]
+	}
+}
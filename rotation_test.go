@@ -0,0 +1,47 @@
+package dns_resolver
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestPickServerRoundRobin(t *testing.T) {
+	r := New([]string{"192.0.2.1", "192.0.2.2", "192.0.2.3"})
+	r.RotationStrategy = RoundRobin
+
+	want := []string{r.Servers[0], r.Servers[1], r.Servers[2], r.Servers[0]}
+	for i, w := range want {
+		if got := r.pickServer(); got != w {
+			t.Errorf("pick %d = %q, want %q", i, got, w)
+		}
+	}
+}
+
+func TestPickServerSticky(t *testing.T) {
+	r := New([]string{"192.0.2.1", "192.0.2.2"})
+	r.RotationStrategy = Sticky
+
+	first := r.pickServer()
+	for i := 0; i < 5; i++ {
+		if got := r.pickServer(); got != first {
+			t.Errorf("sticky pick %d = %q, want %q", i, got, first)
+		}
+	}
+}
+
+// TestPickServerRandomConcurrent exercises pickServer's default Random
+// case from many goroutines at once; run with -race to catch the
+// rand.Rand data race this guards against.
+func TestPickServerRandomConcurrent(t *testing.T) {
+	r := New([]string{"192.0.2.1", "192.0.2.2", "192.0.2.3"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.pickServer()
+		}()
+	}
+	wg.Wait()
+}
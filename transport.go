@@ -0,0 +1,194 @@
+package dns_resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// defaultTLSPort is used for tcp-tls (DNS over TLS, RFC 7858) when a server
+// was only given a plain address and New appended the default port :53.
+const defaultTLSPort = "853"
+
+// defaultMaxConnsPerServer bounds the number of pooled connections kept
+// open to a single proto://address when MaxConnsPerServer is unset,
+// preserving the historical "one reused connection" behavior.
+const defaultMaxConnsPerServer = 1
+
+// dialAddress adjusts address for the tcp-tls default port when the caller
+// never overrode the default :53, preserving any IPv6 zone.
+func (r *MiekgResolver) dialAddress(proto, address string) string {
+	if proto != "tcp-tls" {
+		return address
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || port != "53" {
+		return address
+	}
+	return net.JoinHostPort(host, defaultTLSPort)
+}
+
+// pooledConn is a single pooled connection. mu serializes the write+read
+// of one query/response pair on it, since *dns.Conn itself isn't safe for
+// concurrent use and nothing else matches a response back to the query
+// that sent it.
+type pooledConn struct {
+	mu   sync.Mutex
+	conn *dns.Conn
+}
+
+// connPool is a concurrency-safe, bounded set of pooled connections for a
+// single proto://address, checked out round-robin.
+type connPool struct {
+	mu    sync.Mutex
+	conns []*pooledConn
+	next  int
+}
+
+func (r *MiekgResolver) poolFor(key string) *connPool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.conns[key]
+	if !ok {
+		p = &connPool{}
+		r.conns[key] = p
+	}
+	return p
+}
+
+// getConnection returns a connection from the pool for proto://address,
+// dialing a new one if the pool has room, or reusing the least recently
+// checked out one once MaxConnsPerServer is reached.
+func (r *MiekgResolver) getConnection(proto, address string) (*pooledConn, error) {
+	max := r.MaxConnsPerServer
+	if max <= 0 {
+		max = defaultMaxConnsPerServer
+	}
+
+	key := proto + "://" + address
+	pool := r.poolFor(key)
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if len(pool.conns) < max {
+		c, err := r.dial(proto, address)
+		if err != nil {
+			return nil, err
+		}
+		pool.conns = append(pool.conns, &pooledConn{conn: c})
+	}
+
+	pc := pool.conns[pool.next%len(pool.conns)]
+	pool.next++
+	return pc, nil
+}
+
+func (r *MiekgResolver) dial(proto, address string) (*dns.Conn, error) {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = dnsTimeout
+	}
+	switch {
+	case r.DialFunc != nil:
+		return r.DialFunc(proto, address)
+	case proto == "tcp-tls":
+		return dns.DialTimeoutWithTLS("tcp", address, r.TLSConfig, timeout)
+	default:
+		return dns.DialTimeout(proto, address, timeout)
+	}
+}
+
+// evictConnection drops a connection that failed an I/O operation so the
+// next getConnection call for the same key dials a fresh one.
+func (r *MiekgResolver) evictConnection(proto, address string, bad *pooledConn) {
+	key := proto + "://" + address
+	pool := r.poolFor(key)
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for i, c := range pool.conns {
+		if c == bad {
+			pool.conns = append(pool.conns[:i], pool.conns[i+1:]...)
+			break
+		}
+	}
+}
+
+// attemptTimeout returns the deadline for a single query attempt: the
+// configured Timeout (or the package default), capped to the ctx
+// deadline's remaining time divided across the attempts still available.
+func (r *MiekgResolver) attemptTimeout(ctx context.Context, attemptsLeft int) time.Duration {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = dnsTimeout
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if attemptsLeft < 1 {
+			attemptsLeft = 1
+		}
+		if remaining := time.Until(deadline) / time.Duration(attemptsLeft); remaining < timeout {
+			timeout = remaining
+		}
+	}
+	return timeout
+}
+
+// exchange sends m1 to server over proto ("udp", "tcp" or "tcp-tls"),
+// reusing a pooled connection when ReuseConnection is set. attemptsLeft is
+// the number of attempts (including this one) still available under ctx,
+// used to divide up ctx's remaining deadline.
+func (r *MiekgResolver) exchange(ctx context.Context, m1 *dns.Msg, server, proto string, attemptsLeft int) (*dns.Msg, error) {
+	address := r.dialAddress(proto, server)
+	timeout := r.attemptTimeout(ctx, attemptsLeft)
+
+	if r.ReuseConnection {
+		pc, err := r.getConnection(proto, address)
+		if err != nil {
+			return nil, err
+		}
+
+		pc.mu.Lock()
+		defer pc.mu.Unlock()
+
+		pc.conn.SetDeadline(time.Now().Add(timeout))
+		if err := pc.conn.WriteMsg(m1); err != nil {
+			r.evictConnection(proto, address, pc)
+			return nil, err
+		}
+		in, err := pc.conn.ReadMsg()
+		if err != nil {
+			r.evictConnection(proto, address, pc)
+			return nil, err
+		}
+		if in.Id != m1.Id {
+			r.evictConnection(proto, address, pc)
+			return nil, errors.New("dns_resolver: got response for unexpected transaction id")
+		}
+		return in, nil
+	}
+
+	if r.DialFunc != nil {
+		c, err := r.DialFunc(proto, address)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+		c.SetDeadline(time.Now().Add(timeout))
+		if err := c.WriteMsg(m1); err != nil {
+			return nil, err
+		}
+		return c.ReadMsg()
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client := &dns.Client{Net: proto, Timeout: timeout, TLSConfig: r.TLSConfig}
+	in, _, err := client.ExchangeContext(attemptCtx, m1, address)
+	return in, err
+}
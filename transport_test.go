@@ -0,0 +1,66 @@
+package dns_resolver
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// TestExchangeReuseConnectionPairsResponses uses DialFunc to hand the
+// resolver a fake dns.Conn (backed by net.Pipe) instead of a real socket,
+// then fires concurrent lookups over the single pooled connection and
+// checks each gets back its own answer rather than another query's.
+func TestExchangeReuseConnectionPairsResponses(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	r := New([]string{"203.0.113.1"})
+	r.ReuseConnection = true
+	r.DialFunc = func(network, address string) (*dns.Conn, error) {
+		return &dns.Conn{Conn: client}, nil
+	}
+
+	names := []string{"first.example.", "second.example."}
+
+	srv := &dns.Conn{Conn: server}
+	go func() {
+		for range names {
+			req, err := srv.ReadMsg()
+			if err != nil {
+				return
+			}
+			resp := new(dns.Msg)
+			resp.SetReply(req)
+			resp.Answer = append(resp.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+				Txt: []string{req.Question[0].Name},
+			})
+			srv.WriteMsg(resp)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			in, err := r.Lookup(name, dns.TypeTXT)
+			if err != nil {
+				t.Errorf("Lookup(%q): %v", name, err)
+				return
+			}
+			if len(in.Answer) != 1 {
+				t.Errorf("Lookup(%q): got %d answers, want 1", name, len(in.Answer))
+				return
+			}
+			txt, ok := in.Answer[0].(*dns.TXT)
+			if !ok || len(txt.Txt) != 1 || txt.Txt[0] != name {
+				t.Errorf("Lookup(%q): got answer %v, want echo of the request name", name, in.Answer[0])
+			}
+		}(name)
+	}
+	wg.Wait()
+}